@@ -1,21 +1,51 @@
 package main
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"fido2/m/config"
 	"fido2/m/domain"
+	"fido2/m/oidc"
+	"fido2/m/session"
+	"fido2/m/storage"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"sync"
+	"path/filepath"
+	"time"
 
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 )
 
+// sessionCookieName is the cookie the session manager uses to carry
+// in-flight registration/login ceremony state between begin and finish.
+const sessionCookieName = "fido2-session"
+
+// loginChallengeCookieName carries a pending Hydra login_challenge across
+// the redirect from /login to the passkey UI and back through the
+// existing loginFinish/loginFinishDiscoverable endpoints.
+const loginChallengeCookieName = "fido2-login-challenge"
+
+// identityCookieName carries the signed-in user's identity once a
+// registration or login ceremony succeeds, so the self-service credential
+// management API can tell who is calling without the caller re-asserting a
+// username. Unlike sessionCookieName it outlives a single ceremony.
+const identityCookieName = "fido2-identity"
+
+// identityTTL bounds how long a signed-in identity cookie is honored before
+// the caller has to authenticate again.
+const identityTTL = 24 * time.Hour
+
 var (
 	webauthnInstance *webauthn.WebAuthn
-	users            = make(map[string]*domain.User)
-	sessionData      = sync.Map{} // Thread-safe storage for session data
+	credentialStore  storage.CredentialStore
+	sessionManager   *session.Manager
+	adminClient      *oidc.AdminClient
+	staticDir        string
 )
 
 type Logger interface {
@@ -23,11 +53,23 @@ type Logger interface {
 }
 
 func main() {
+	if err := initializeStorage(); err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	if err := initializeSession(); err != nil {
+		log.Fatalf("Failed to initialize session manager: %v", err)
+	}
+
+	if err := initializeAdmin(); err != nil {
+		log.Fatalf("Failed to initialize OIDC admin client: %v", err)
+	}
+
 	if err := initializeWebAuthn(); err != nil {
 		log.Fatalf("Failed to initialize WebAuthn: %v", err)
 	}
 
-	staticDir := os.Getenv("STATIC_DIR")
+	staticDir = os.Getenv("STATIC_DIR")
 	if staticDir == "" {
 		staticDir = "web"
 	}
@@ -37,24 +79,142 @@ func main() {
 	http.HandleFunc("/api/passkey/registerFinish", finishRegistration)
 	http.HandleFunc("/api/passkey/loginStart", beginAuthentication)
 	http.HandleFunc("/api/passkey/loginFinish", finishAuthentication)
+	http.HandleFunc("/api/passkey/loginStartDiscoverable", beginDiscoverableLogin)
+	http.HandleFunc("/api/passkey/loginFinishDiscoverable", finishDiscoverableLogin)
+	http.HandleFunc("GET /api/passkey/credentials", listCredentials)
+	http.HandleFunc("PATCH /api/passkey/credentials/{id}", renameCredential)
+	http.HandleFunc("DELETE /api/passkey/credentials/{id}", deleteCredential)
+	http.HandleFunc("/login", loginHandler)
+	http.HandleFunc("/consent", consentHandler)
 
 	fmt.Printf("Starting FIDO2 server on :8080")
-	http.ListenAndServe(":8080", nil)
+	http.ListenAndServe(":8080", withCORS(http.DefaultServeMux))
+}
+
+// withCORS answers CORS preflight requests directly, rather than relying on
+// setHeaders being reached from within each handler. Go 1.22's method-specific
+// mux patterns (e.g. "PATCH /api/passkey/credentials/{id}") only match that
+// one method, so http.ServeMux responds 405 to an OPTIONS preflight before a
+// handler ever runs; multi-origin clients (see config.RPConfig) would have
+// every PATCH/DELETE blocked by the browser as a result.
+func withCORS(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// initializeStorage wires up the CredentialStore backend. STORAGE_BACKEND
+// selects the implementation ("memory", the default, or "sqlite");
+// SQLITE_PATH selects the database file for the latter.
+func initializeStorage() error {
+	backend := os.Getenv("STORAGE_BACKEND")
+	if backend == "" {
+		backend = "memory"
+	}
+
+	switch backend {
+	case "memory":
+		credentialStore = storage.NewMemoryStore()
+		return nil
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "fido2.db"
+		}
+		store, err := storage.NewSQLiteStore(path)
+		if err != nil {
+			return err
+		}
+		credentialStore = store
+		return nil
+	default:
+		return fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+// initializeSession wires up the session.Manager that carries in-flight
+// ceremony state in an encrypted cookie. SESSION_KEY, if set, is a
+// base64-encoded 16/24/32-byte AES key; otherwise a key is generated for
+// the lifetime of the process, which is fine for the cookie backend since
+// sessions are short-lived (session.DefaultTTL) but means any sessions in
+// flight at restart are invalidated.
+func initializeSession() error {
+	key, err := sessionKey()
+	if err != nil {
+		return fmt.Errorf("resolve session key: %w", err)
+	}
+
+	sessionManager, err = session.NewCookieManager(key)
+	return err
+}
+
+func sessionKey() ([]byte, error) {
+	encoded := os.Getenv("SESSION_KEY")
+	if encoded == "" {
+		log.Printf("SESSION_KEY not set; generating an ephemeral key for this process")
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("SESSION_KEY is not valid base64: %w", err)
+	}
+	return key, nil
+}
+
+// initializeAdmin wires up the OIDC admin client that lets this service
+// act as a Hydra login/consent provider. It is optional: if OIDC_ADMIN_URL
+// is unset, adminClient stays nil and /login and /consent respond 501, but
+// the plain passkey API still works.
+func initializeAdmin() error {
+	cfg, err := config.LoadAdmin()
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return nil
+	}
+
+	adminClient = oidc.NewAdminClient(cfg.BaseURL, cfg.Token)
+	log.Printf("OIDC identity-provider mode enabled against admin API %s", cfg.BaseURL)
+	return nil
 }
 
 func initializeWebAuthn() error {
-	var err error
+	rpConfig, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if err := rpConfig.Validate(); err != nil {
+		return err
+	}
+
 	webauthnInstance, err = webauthn.New(&webauthn.Config{
-		RPDisplayName: "FIDO2 Example",                   // Display name for the Relying Party
-		RPID:          "localhost",                       // Relying Party ID (domain name)
-		RPOrigins:     []string{"http://localhost:8080"}, // Relying Party Origin
+		RPDisplayName:         rpConfig.DisplayName,
+		RPID:                  rpConfig.ID,
+		RPOrigins:             rpConfig.Origins,
+		RPTopOrigins:          rpConfig.TopOrigins,
+		AttestationPreference: rpConfig.Attestation(),
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	log.Printf("WebAuthn RP %q initialized: origins=%v topOrigins=%v attestation=%s",
+		rpConfig.ID, rpConfig.Origins, rpConfig.TopOrigins, rpConfig.Attestation())
+	return nil
 }
 
 func beginRegistration(w http.ResponseWriter, r *http.Request) {
-	setHeaders(w)
-
 	username, err := getUsername(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -62,8 +222,23 @@ func beginRegistration(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := getOrCreateUser(username)
-	credential, data, err := webauthnInstance.BeginRegistration(user)
+	user, err := getOrCreateUser(username)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load user."))
+		return
+	}
+	if err := user.LoadErr(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load existing credentials."))
+		return
+	}
+
+	credential, data, err := webauthnInstance.BeginRegistration(
+		user,
+		requireResidentKey(),
+		webauthn.WithExclusions(user.CredentialExcludeList()),
+	)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Failed to start registration."))
@@ -72,31 +247,37 @@ func beginRegistration(w http.ResponseWriter, r *http.Request) {
 
 	prettyPrint("Create registration credential", credential)
 
-	sessionData.Store(string(user.WebAuthnID()), data)
+	if err := sessionManager.Save(w, sessionCookieName, data); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to store session data."))
+		return
+	}
 
 	writeJSON(w, credential)
 }
 
 func finishRegistration(w http.ResponseWriter, r *http.Request) {
-	setHeaders(w)
-
-	username := r.URL.Query().Get("username")
-	if username == "" {
+	data, err := sessionManager.Load(r, sessionCookieName)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Username is required."))
+		w.Write([]byte("Users session data not found."))
 		return
 	}
+	sessionManager.Clear(w, r, sessionCookieName)
 
-	user := getOrCreateUser(username)
-	data, ok := sessionData.Load(string(user.WebAuthnID()))
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Users session data not found."))
+	user, err := resolveSessionUser(data.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load user."))
+		return
+	}
+	if err := user.LoadErr(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load existing credentials."))
 		return
 	}
-	sessionData.Delete(string(user.WebAuthnID()))
 
-	credential, err := webauthnInstance.FinishRegistration(user, *data.(*webauthn.SessionData), r)
+	credential, err := webauthnInstance.FinishRegistration(user, *data, r)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Failed to finish registration."))
@@ -105,14 +286,22 @@ func finishRegistration(w http.ResponseWriter, r *http.Request) {
 
 	prettyPrint("Verified registration credential", credential)
 
-	user.AddCredential(*credential)
+	if err := credentialStore.AddCredential(user.WebAuthnID(), *credential); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to persist credential."))
+		return
+	}
+
+	if err := setIdentity(w, user.WebAuthnID()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to establish identity."))
+		return
+	}
 
 	writeJSON(w, map[string]string{"status": "registration successful"})
 }
 
 func beginAuthentication(w http.ResponseWriter, r *http.Request) {
-	setHeaders(w)
-
 	username, err := getUsername(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -120,7 +309,29 @@ func beginAuthentication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := getOrCreateUser(username)
+	record, err := credentialStore.GetUser(username)
+	if err == storage.ErrNotFound {
+		// Do not create a user record here: unlike registration, a login
+		// attempt names an account the caller claims already exists, and
+		// an unauthenticated caller can name any username it likes. Since
+		// storage now persists across restarts, creating one per attempt
+		// would let login attempts alone grow the user table without
+		// bound.
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("User not found."))
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load user."))
+		return
+	}
+	user := domain.NewUser(record.ID, record.Name, record.DisplayName, credentialStore)
+	if err := user.LoadErr(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load credentials."))
+		return
+	}
+
 	credential, data, err := webauthnInstance.BeginLogin(user)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -129,55 +340,450 @@ func beginAuthentication(w http.ResponseWriter, r *http.Request) {
 	}
 	prettyPrint("Created assertion credential", credential)
 
-	sessionData.Store(string(user.WebAuthnID()), data)
+	if err := sessionManager.Save(w, sessionCookieName, data); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to store session data."))
+		return
+	}
 
 	writeJSON(w, credential)
 }
 
 func finishAuthentication(w http.ResponseWriter, r *http.Request) {
-	setHeaders(w)
-
-	username := r.URL.Query().Get("username")
-	if username == "" {
+	data, err := sessionManager.Load(r, sessionCookieName)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Username is required."))
+		w.Write([]byte("Users session data not found."))
 		return
 	}
+	sessionManager.Clear(w, r, sessionCookieName)
 
-	user := getOrCreateUser(username)
+	user, err := resolveSessionUser(data.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load user."))
+		return
+	}
+	if err := user.LoadErr(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load credentials."))
+		return
+	}
 
-	data, ok := sessionData.Load(string(user.WebAuthnID()))
-	if !ok {
+	credential, err := webauthnInstance.FinishLogin(user, *data, r)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to finish authentication."))
+		return
+	}
+
+	prettyPrint("Validated credential", credential)
+
+	if err := credentialStore.UpdateCredentialCounter(credential.ID, credential.Authenticator.SignCount); err != nil {
+		log.Printf("failed to persist updated sign counter for credential %x: %v", credential.ID, err)
+	}
+
+	if err := setIdentity(w, user.WebAuthnID()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to establish identity."))
+		return
+	}
+
+	redirectTo, err := finishIdPLogin(w, r, user.WebAuthnID())
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("Failed to complete identity-provider login."))
+		return
+	}
+	if redirectTo != "" {
+		writeJSON(w, map[string]string{"redirectTo": redirectTo})
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "authentication successful"})
+}
+
+// requireResidentKey asks the authenticator to create a discoverable
+// (resident key) credential with user verification, so it can later be
+// used in a usernameless login via beginDiscoverableLogin.
+func requireResidentKey() webauthn.RegistrationOption {
+	return webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+		ResidentKey:      protocol.ResidentKeyRequirementRequired,
+		UserVerification: protocol.VerificationRequired,
+	})
+}
+
+func beginDiscoverableLogin(w http.ResponseWriter, r *http.Request) {
+	credential, data, err := webauthnInstance.BeginDiscoverableLogin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to start authentication."))
+		return
+	}
+	prettyPrint("Created discoverable assertion credential", credential)
+
+	if err := sessionManager.Save(w, sessionCookieName, data); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to store session data."))
+		return
+	}
+
+	writeJSON(w, credential)
+}
+
+func finishDiscoverableLogin(w http.ResponseWriter, r *http.Request) {
+	data, err := sessionManager.Load(r, sessionCookieName)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("Users session data not found."))
 		return
 	}
-	sessionData.Delete(string(user.WebAuthnID()))
+	sessionManager.Clear(w, r, sessionCookieName)
+
+	var userHandle []byte
+	resolveAndRemember := func(rawID, handle []byte) (webauthn.User, error) {
+		user, err := resolveDiscoverableUser(rawID, handle)
+		if err == nil {
+			userHandle = handle
+		}
+		return user, err
+	}
 
-	credential, err := webauthnInstance.FinishLogin(user, *data.(*webauthn.SessionData), r)
+	credential, err := webauthnInstance.FinishDiscoverableLogin(resolveAndRemember, *data, r)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Failed to finish authentication."))
+		return
 	}
 
-	prettyPrint("Validated credential", credential)
+	prettyPrint("Validated discoverable credential", credential)
+
+	if err := credentialStore.UpdateCredentialCounter(credential.ID, credential.Authenticator.SignCount); err != nil {
+		log.Printf("failed to persist updated sign counter for credential %x: %v", credential.ID, err)
+	}
+
+	if err := setIdentity(w, userHandle); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to establish identity."))
+		return
+	}
+
+	redirectTo, err := finishIdPLogin(w, r, userHandle)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("Failed to complete identity-provider login."))
+		return
+	}
+	if redirectTo != "" {
+		writeJSON(w, map[string]string{"redirectTo": redirectTo})
+		return
+	}
 
 	writeJSON(w, map[string]string{"status": "authentication successful"})
 }
 
-func getOrCreateUser(username string) *domain.User {
-	if user, exists := users[username]; exists {
-		return user
+// resolveDiscoverableUser implements webauthn.DiscoverableUserHandler,
+// turning the userHandle returned in a discoverable assertion back into a
+// domain.User via the credential store.
+func resolveDiscoverableUser(rawID, userHandle []byte) (webauthn.User, error) {
+	record, err := credentialStore.GetUserByID(userHandle)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewUser(record.ID, record.Name, record.DisplayName, credentialStore), nil
+}
+
+// loginHandler implements the browser-facing half of the Ory Hydra login
+// flow: Hydra redirects here with a login_challenge when an OAuth2 client
+// starts an authorization request. If the browser already has a Hydra SSO
+// session (login.Skip), we accept immediately with the same subject;
+// otherwise we remember the challenge in a cookie and hand off to the
+// passkey UI in staticDir, which drives the existing /api/passkey
+// endpoints. Those endpoints complete the login via finishIdPLogin once
+// the passkey ceremony succeeds.
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if adminClient == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		w.Write([]byte("OIDC identity-provider mode is not configured."))
+		return
+	}
+
+	challenge := r.URL.Query().Get("login_challenge")
+	if challenge == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("login_challenge is required."))
+		return
+	}
+
+	login, err := adminClient.GetLoginRequest(challenge)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("Failed to resolve login request."))
+		return
+	}
+
+	if login.Skip {
+		redirectTo, err := adminClient.AcceptLoginRequest(challenge, login.Subject, true)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("Failed to accept login request."))
+			return
+		}
+		http.Redirect(w, r, redirectTo, http.StatusFound)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginChallengeCookieName,
+		Value:    challenge,
+		Path:     "/",
+		MaxAge:   int(session.DefaultTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.ServeFile(w, r, filepath.Join(staticDir, "index.html"))
+}
+
+// finishIdPLogin accepts the Hydra login request named by the pending
+// login_challenge cookie, if any, on behalf of userID, and returns the URL
+// the caller should redirect to next (typically Hydra's consent
+// endpoint). It returns ("", nil) when there is no IdP login in flight, so
+// the plain passkey API is unaffected.
+func finishIdPLogin(w http.ResponseWriter, r *http.Request, userID []byte) (redirectTo string, err error) {
+	cookie, err := r.Cookie(loginChallengeCookieName)
+	if err != nil {
+		return "", nil
+	}
+
+	redirectTo, err = adminClient.AcceptLoginRequest(cookie.Value, oidc.Subject(userID), true)
+	if err != nil {
+		// Leave the cookie in place on failure: the passkey ceremony
+		// already succeeded, so the caller is expected to retry
+		// completing the IdP hand-off rather than restart the whole
+		// OAuth2 authorization request.
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   loginChallengeCookieName,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
+	return redirectTo, nil
+}
+
+// consentHandler auto-grants every scope and audience Hydra reports the
+// OAuth2 client requested. There is no end-user consent screen because
+// this service is meant to front a single first-party client for its
+// operator; a multi-tenant deployment would replace this with a real
+// consent UI.
+func consentHandler(w http.ResponseWriter, r *http.Request) {
+	if adminClient == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		w.Write([]byte("OIDC identity-provider mode is not configured."))
+		return
+	}
+
+	challenge := r.URL.Query().Get("consent_challenge")
+	if challenge == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("consent_challenge is required."))
+		return
 	}
 
-	user := &domain.User{
-		ID:          []byte(username), // Unique ID
-		Name:        username,
-		DisplayName: username,
-		Credentials: []webauthn.Credential{},
+	consent, err := adminClient.GetConsentRequest(challenge)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("Failed to resolve consent request."))
+		return
 	}
-	users[username] = user
-	return user
+
+	redirectTo, err := adminClient.AcceptConsentRequest(challenge, consent.RequestedScope, consent.RequestedAccessTokenAudience, true)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("Failed to accept consent request."))
+		return
+	}
+
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
+
+// credentialView is the JSON representation of a CredentialRecord returned
+// by the self-service credential management API.
+type credentialView struct {
+	ID         string     `json:"id"`
+	Nickname   string     `json:"nickname"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+func listCredentials(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+
+	credRecords, err := credentialStore.ListCredentialRecords(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to list credentials."))
+		return
+	}
+
+	views := make([]credentialView, len(credRecords))
+	for i, cr := range credRecords {
+		views[i] = credentialView{
+			ID:        base64.RawURLEncoding.EncodeToString(cr.Credential.ID),
+			Nickname:  cr.Nickname,
+			CreatedAt: cr.CreatedAt,
+		}
+		if !cr.LastUsedAt.IsZero() {
+			lastUsedAt := cr.LastUsedAt
+			views[i].LastUsedAt = &lastUsedAt
+		}
+	}
+
+	writeJSON(w, views)
+}
+
+func renameCredential(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid credential id."))
+		return
+	}
+
+	if !callerOwnsCredential(userID, credentialID) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Credential not found."))
+		return
+	}
+
+	var body struct {
+		Nickname string `json:"nickname"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid request body."))
+		return
+	}
+
+	if err := credentialStore.RenameCredential(credentialID, body.Nickname); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Credential not found."))
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "credential updated"})
+}
+
+func deleteCredential(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid credential id."))
+		return
+	}
+
+	if !callerOwnsCredential(userID, credentialID) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Credential not found."))
+		return
+	}
+
+	if err := credentialStore.DeleteCredential(credentialID); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Credential not found."))
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "credential deleted"})
+}
+
+// callerOwnsCredential reports whether credentialID is enrolled against
+// userID, so renameCredential and deleteCredential can reject a caller
+// acting on another user's credential with the same 404 they'd get for an
+// ID that doesn't exist at all, rather than leaking which IDs belong to
+// someone else.
+func callerOwnsCredential(userID, credentialID []byte) bool {
+	_, owner, err := credentialStore.FindCredentialByID(credentialID)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(owner, userID)
+}
+
+// setIdentity marks the caller as signed in as userID, so a later call to
+// the self-service credential management API can be scoped to their own
+// credentials without re-asserting a username.
+func setIdentity(w http.ResponseWriter, userID []byte) error {
+	return sessionManager.SaveValue(w, identityCookieName, identityTTL, &identityCookie{UserID: userID})
+}
+
+// requireIdentity resolves the caller's signed-in user ID from the identity
+// cookie set by setIdentity, writing a 401 response and returning ok=false
+// if there isn't one.
+func requireIdentity(w http.ResponseWriter, r *http.Request) (userID []byte, ok bool) {
+	var identity identityCookie
+	if err := sessionManager.LoadValue(r, identityCookieName, &identity); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Authentication required."))
+		return nil, false
+	}
+	return identity.UserID, true
+}
+
+// identityCookie is the value carried by identityCookieName.
+type identityCookie struct {
+	UserID []byte `json:"userId"`
+}
+
+// resolveSessionUser turns the UserID carried in a webauthn.SessionData
+// back into a domain.User, so finishRegistration and finishAuthentication
+// no longer need the caller to re-send a username alongside the cookie.
+func resolveSessionUser(userID []byte) (*domain.User, error) {
+	record, err := credentialStore.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewUser(record.ID, record.Name, record.DisplayName, credentialStore), nil
+}
+
+// getOrCreateUser resolves username to a domain.User backed by the
+// configured CredentialStore, creating and persisting a new user record on
+// first sight. It is only used by beginRegistration: an unauthenticated
+// caller is allowed to claim a new username by registering a passkey for
+// it, but merely attempting a login for an unknown username must not
+// persist anything (see beginAuthentication).
+func getOrCreateUser(username string) (*domain.User, error) {
+	record, err := credentialStore.GetUser(username)
+	if err == storage.ErrNotFound {
+		record = &storage.UserRecord{
+			ID:          []byte(username),
+			Name:        username,
+			DisplayName: username,
+		}
+		if err := credentialStore.PutUser(record); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return domain.NewUser(record.ID, record.Name, record.DisplayName, credentialStore), nil
 }
 
 func getUsername(r *http.Request) (string, error) {
@@ -194,7 +800,7 @@ func getUsername(r *http.Request) (string, error) {
 
 func setHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*") // Use "*" for any origin, or specify a domain
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 }
 
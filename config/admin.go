@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// AdminConfig configures the client used to resolve OAuth2/OIDC login and
+// consent challenges against an upstream Ory Hydra-compatible admin API,
+// turning this service into a passkey-authenticated identity provider.
+type AdminConfig struct {
+	BaseURL string
+	Token   string
+}
+
+// LoadAdmin resolves the admin API configuration from OIDC_ADMIN_URL and
+// OIDC_ADMIN_TOKEN. It returns a nil AdminConfig (and nil error) if
+// OIDC_ADMIN_URL is unset, so identity-provider mode stays opt-in for
+// deployments that only want the plain passkey API.
+func LoadAdmin() (*AdminConfig, error) {
+	baseURL := os.Getenv("OIDC_ADMIN_URL")
+	if baseURL == "" {
+		return nil, nil
+	}
+
+	token := os.Getenv("OIDC_ADMIN_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("config: OIDC_ADMIN_TOKEN is required when OIDC_ADMIN_URL is set")
+	}
+
+	return &AdminConfig{BaseURL: baseURL, Token: token}, nil
+}
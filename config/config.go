@@ -0,0 +1,141 @@
+// Package config resolves the Relying Party configuration used to
+// initialize go-webauthn, so the same server can be pointed at different
+// RP IDs and origin sets (browser, iOS associated domains, Android
+// Play-Services FIDO2ApiClient) without a code change.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// androidOriginPrefix identifies an Android Play-Services FIDO2ApiClient
+// origin, e.g. "android:apk-key-hash:<base64 cert hash>".
+const androidOriginPrefix = "android:apk-key-hash:"
+
+// RPConfig holds the Relying Party settings passed to webauthn.Config.
+type RPConfig struct {
+	DisplayName           string   `yaml:"displayName"`
+	ID                    string   `yaml:"id"`
+	Origins               []string `yaml:"origins"`
+	TopOrigins            []string `yaml:"topOrigins"`
+	AttestationPreference string   `yaml:"attestationPreference"`
+}
+
+// Load resolves the RP configuration. If CONFIG_FILE is set it is read as
+// YAML; otherwise the configuration comes from RP_DISPLAY_NAME, RP_ID,
+// RP_ORIGINS, RP_TOP_ORIGINS (comma-separated) and
+// RP_ATTESTATION_PREFERENCE, falling back to single-origin localhost
+// defaults suitable for local development.
+func Load() (*RPConfig, error) {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return loadFile(path)
+	}
+	return loadEnv(), nil
+}
+
+func loadFile(path string) (*RPConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: read %s: %w", path, err)
+	}
+
+	var cfg RPConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func loadEnv() *RPConfig {
+	cfg := &RPConfig{
+		DisplayName:           os.Getenv("RP_DISPLAY_NAME"),
+		ID:                    os.Getenv("RP_ID"),
+		Origins:               splitCSV(os.Getenv("RP_ORIGINS")),
+		TopOrigins:            splitCSV(os.Getenv("RP_TOP_ORIGINS")),
+		AttestationPreference: os.Getenv("RP_ATTESTATION_PREFERENCE"),
+	}
+
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = "FIDO2 Example"
+	}
+	if cfg.ID == "" {
+		cfg.ID = "localhost"
+	}
+	if len(cfg.Origins) == 0 {
+		cfg.Origins = []string{"http://localhost:8080"}
+	}
+	return cfg
+}
+
+func splitCSV(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// Validate rejects configurations that mix insecure (plain http, beyond
+// localhost) origins with secure ones, since browsers and authenticators
+// will not treat such a Relying Party consistently.
+func (c *RPConfig) Validate() error {
+	if len(c.Origins) == 0 {
+		return fmt.Errorf("config: at least one RP origin is required")
+	}
+
+	var sawSecure, sawInsecure bool
+	for _, origin := range append(append([]string{}, c.Origins...), c.TopOrigins...) {
+		if isSecureOrigin(origin) {
+			sawSecure = true
+		} else {
+			sawInsecure = true
+		}
+	}
+
+	if sawSecure && sawInsecure {
+		return fmt.Errorf("config: cannot mix insecure http origins with secure origins: %v", c.Origins)
+	}
+	return nil
+}
+
+// isSecureOrigin reports whether origin is acceptable as a WebAuthn RP
+// origin: an https:// web origin, an Android apk-key-hash origin, or the
+// http://localhost exception browsers grant to local development.
+func isSecureOrigin(origin string) bool {
+	switch {
+	case strings.HasPrefix(origin, "https://"):
+		return true
+	case strings.HasPrefix(origin, androidOriginPrefix):
+		return true
+	case strings.HasPrefix(origin, "http://localhost"), strings.HasPrefix(origin, "http://127.0.0.1"):
+		return true
+	default:
+		return false
+	}
+}
+
+// Attestation resolves the configured attestation preference, defaulting
+// to "none" when unset or unrecognized.
+func (c *RPConfig) Attestation() protocol.ConveyancePreference {
+	switch strings.ToLower(c.AttestationPreference) {
+	case "indirect":
+		return protocol.PreferIndirectAttestation
+	case "direct":
+		return protocol.PreferDirectAttestation
+	case "enterprise":
+		return protocol.PreferEnterpriseAttestation
+	default:
+		return protocol.PreferNoAttestation
+	}
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+func TestRPConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RPConfig
+		wantErr bool
+	}{
+		{
+			name: "single https origin",
+			cfg:  RPConfig{Origins: []string{"https://example.com"}},
+		},
+		{
+			name: "web and android origins together",
+			cfg: RPConfig{
+				Origins: []string{
+					"https://example.com",
+					"android:apk-key-hash:LvCwbFaP10c2AJQQmVO8m8l1rH0=",
+				},
+			},
+		},
+		{
+			name: "localhost exception alongside https",
+			cfg: RPConfig{
+				Origins: []string{"https://example.com"},
+				TopOrigins: []string{
+					"http://localhost:8080",
+				},
+			},
+		},
+		{
+			name:    "no origins",
+			cfg:     RPConfig{},
+			wantErr: true,
+		},
+		{
+			name: "mixed insecure and secure origins",
+			cfg: RPConfig{
+				Origins: []string{"https://example.com", "http://example.com"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsSecureOriginAcceptsAndroidApkKeyHash(t *testing.T) {
+	origin := "android:apk-key-hash:LvCwbFaP10c2AJQQmVO8m8l1rH0="
+	if !isSecureOrigin(origin) {
+		t.Fatalf("isSecureOrigin(%q) = false, want true", origin)
+	}
+}
+
+// TestRegistrationAcceptsAndroidApkKeyHashOrigin proves that an RPConfig
+// carrying an apk-key-hash origin is actually honored by go-webauthn's own
+// origin-matching logic during a registration ceremony, not merely by this
+// package's isSecureOrigin/Validate helpers. It synthesizes the attestation
+// response a real Android FIDO2ApiClient would produce (a "none"-format
+// attestation object over a freshly generated ES256 key) with a clientData
+// origin of "android:apk-key-hash:...", and asserts FinishRegistration
+// succeeds against a webauthn.Config built from such an RPConfig.
+func TestRegistrationAcceptsAndroidApkKeyHashOrigin(t *testing.T) {
+	const rpID = "example.com"
+	androidOrigin := "android:apk-key-hash:LvCwbFaP10c2AJQQmVO8m8l1rH0="
+
+	cfg := RPConfig{
+		DisplayName: "Test RP",
+		ID:          rpID,
+		Origins:     []string{androidOrigin},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPDisplayName:         cfg.DisplayName,
+		RPID:                  cfg.ID,
+		RPOrigins:             cfg.Origins,
+		AttestationPreference: cfg.Attestation(),
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New() = %v", err)
+	}
+
+	user := &fakeUser{id: []byte("android-user"), name: "android-user"}
+
+	_, session, err := webAuthn.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() = %v", err)
+	}
+
+	body, err := synthesizeAttestationResponse(rpID, androidOrigin, session.Challenge)
+	if err != nil {
+		t.Fatalf("synthesizeAttestationResponse() = %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/api/passkey/registerFinish", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("http.NewRequest() = %v", err)
+	}
+
+	if _, err := webAuthn.FinishRegistration(user, *session, req); err != nil {
+		t.Fatalf("FinishRegistration() = %v, want nil (apk-key-hash origin should be accepted)", err)
+	}
+}
+
+// fakeUser is a minimal webauthn.User with no enrolled credentials, enough
+// to drive a registration ceremony in tests without pulling in the domain
+// or storage packages.
+type fakeUser struct {
+	id   []byte
+	name string
+}
+
+func (u *fakeUser) WebAuthnID() []byte                         { return u.id }
+func (u *fakeUser) WebAuthnName() string                       { return u.name }
+func (u *fakeUser) WebAuthnDisplayName() string                { return u.name }
+func (u *fakeUser) WebAuthnCredentials() []webauthn.Credential { return nil }
+
+// synthesizeAttestationResponse hand-builds the JSON body an authenticator
+// would POST to registerFinish: a fresh ES256 credential, wrapped in a
+// "none"-format attestation object, alongside a clientDataJSON naming
+// origin as the ceremony's origin and challenge as its stored challenge.
+func synthesizeAttestationResponse(rpID, origin, challenge string) (string, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	xCoord := make([]byte, 32)
+	yCoord := make([]byte, 32)
+	priv.X.FillBytes(xCoord)
+	priv.Y.FillBytes(yCoord)
+
+	pubKeyCBOR, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   2,  // EC2
+			Algorithm: -7, // ES256
+		},
+		Curve:  1, // P-256
+		XCoord: xCoord,
+		YCoord: yCoord,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		return "", err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	var authData []byte
+	authData = append(authData, rpIDHash[:]...)
+	authData = append(authData, 0x45)                // flags: UP | UV | AT
+	authData = append(authData, 0, 0, 0, 0)          // signature counter
+	authData = append(authData, make([]byte, 16)...) // AAGUID, unused by this authenticator
+	credIDLen := make([]byte, 2)
+	binary.BigEndian.PutUint16(credIDLen, uint16(len(credentialID)))
+	authData = append(authData, credIDLen...)
+	authData = append(authData, credentialID...)
+	authData = append(authData, pubKeyCBOR...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	clientData, err := json.Marshal(map[string]string{
+		"type":      "webauthn.create",
+		"challenge": challenge,
+		"origin":    origin,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	body := map[string]any{
+		"id":    base64.RawURLEncoding.EncodeToString(credentialID),
+		"type":  "public-key",
+		"rawId": base64.RawURLEncoding.EncodeToString(credentialID),
+		"response": map[string]any{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestationObject),
+		},
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
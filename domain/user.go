@@ -0,0 +1,105 @@
+// Package domain holds the application's WebAuthn user model, independent
+// of how it is transported (HTTP) or persisted (storage).
+package domain
+
+import (
+	"log"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// CredentialLoader resolves the credentials owned by a user. It is
+// satisfied by storage.CredentialStore; domain deliberately depends on this
+// narrow interface rather than the storage package so the two can evolve
+// independently.
+type CredentialLoader interface {
+	ListCredentialsByUser(userID []byte) ([]webauthn.Credential, error)
+}
+
+// User adapts an application user to the webauthn.User interface expected
+// by go-webauthn. Credentials are not copied into the struct up front;
+// they are fetched from store on first access and cached for the lifetime
+// of the value, so building a User is cheap even for accounts with many
+// enrolled authenticators.
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+
+	store       CredentialLoader
+	credentials []webauthn.Credential
+	loaded      bool
+	loadErr     error
+}
+
+// NewUser creates a User whose credentials are lazily loaded from store.
+func NewUser(id []byte, name, displayName string, store CredentialLoader) *User {
+	return &User{
+		ID:          id,
+		Name:        name,
+		DisplayName: displayName,
+		store:       store,
+	}
+}
+
+func (u *User) WebAuthnID() []byte { return u.ID }
+
+func (u *User) WebAuthnName() string { return u.Name }
+
+func (u *User) WebAuthnDisplayName() string { return u.DisplayName }
+
+func (u *User) WebAuthnCredentials() []webauthn.Credential {
+	u.ensureLoaded()
+	return u.credentials
+}
+
+func (u *User) ensureLoaded() {
+	if u.loaded {
+		return
+	}
+	u.loaded = true
+	if u.store == nil {
+		return
+	}
+	creds, err := u.store.ListCredentialsByUser(u.ID)
+	if err != nil {
+		log.Printf("domain: load credentials for user %q: %v", u.Name, err)
+		u.loadErr = err
+		return
+	}
+	u.credentials = creds
+}
+
+// LoadErr reports the error, if any, from the store lookup that populates
+// the user's credentials. It forces that lookup to happen, same as
+// WebAuthnCredentials, so callers can check it before relying on
+// WebAuthnCredentials or CredentialExcludeList returning an authoritative
+// result rather than an empty one caused by a transient store failure.
+func (u *User) LoadErr() error {
+	u.ensureLoaded()
+	return u.loadErr
+}
+
+// CredentialExcludeList lists the user's already-enrolled credentials as
+// protocol.CredentialDescriptors, for use as a registration's
+// CredentialExcludeList so the same authenticator cannot be enrolled twice.
+func (u *User) CredentialExcludeList() []protocol.CredentialDescriptor {
+	creds := u.WebAuthnCredentials()
+	exclude := make([]protocol.CredentialDescriptor, len(creds))
+	for i, cred := range creds {
+		exclude[i] = protocol.CredentialDescriptor{
+			Type:         protocol.PublicKeyCredentialType,
+			CredentialID: cred.ID,
+			Transport:    cred.Transport,
+		}
+	}
+	return exclude
+}
+
+// AddCredential appends cred to the user's cached credential set. It does
+// not persist cred; callers must also write it through a CredentialStore.
+func (u *User) AddCredential(cred webauthn.Credential) {
+	u.ensureLoaded()
+	u.credentials = append(u.credentials, cred)
+}
@@ -0,0 +1,160 @@
+// Package oidc implements a minimal client for the Ory Hydra OAuth2/OIDC
+// admin API, so that this service can act as the login (and consent)
+// screen for an upstream OAuth2 server: the upstream redirects the
+// browser here with a login_challenge, we authenticate the user with a
+// passkey, and then call back into the admin API to tell Hydra who logged
+// in and where to send the browser next.
+package oidc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds how long a single admin API call may take, so a
+// slow or unreachable Hydra instance fails the request instead of hanging
+// the handler that is blocked waiting on it.
+const requestTimeout = 10 * time.Second
+
+// LoginRequest describes a pending Hydra login challenge, as returned by
+// GetLoginRequest.
+type LoginRequest struct {
+	Challenge string `json:"challenge"`
+	// Skip is true when the end-user already has a valid Hydra session
+	// for this client, meaning the login request should be accepted
+	// immediately with the same Subject rather than prompting again.
+	Skip    bool   `json:"skip"`
+	Subject string `json:"subject"`
+}
+
+// ConsentRequest describes a pending Hydra consent challenge, as returned
+// by GetConsentRequest.
+type ConsentRequest struct {
+	Challenge                    string   `json:"challenge"`
+	Subject                      string   `json:"subject"`
+	RequestedScope               []string `json:"requested_scope"`
+	RequestedAccessTokenAudience []string `json:"requested_access_token_audience"`
+	Skip                         bool     `json:"skip"`
+}
+
+// acceptResponse is the shape shared by Hydra's login/accept and
+// consent/accept endpoints: the URL to send the browser to next.
+type acceptResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// AdminClient calls the Hydra admin API. It holds a bearer token rather
+// than per-request credentials because the admin API is only ever reached
+// from this service's backend, never the browser.
+type AdminClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewAdminClient creates an AdminClient targeting baseURL (e.g.
+// "https://hydra.internal:4445"), authenticating with token.
+func NewAdminClient(baseURL, token string) *AdminClient {
+	return &AdminClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// GetLoginRequest fetches the login request identified by challenge.
+func (c *AdminClient) GetLoginRequest(challenge string) (*LoginRequest, error) {
+	var out LoginRequest
+	if err := c.do(http.MethodGet, "/oauth2/auth/requests/login", "login_challenge", challenge, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AcceptLoginRequest tells Hydra that subject successfully authenticated
+// for the login request identified by challenge, and returns the URL the
+// browser should be redirected to next (typically the consent endpoint).
+// remember asks Hydra to set its own SSO session cookie, so a subsequent
+// login request for the same browser comes back with Skip set.
+func (c *AdminClient) AcceptLoginRequest(challenge, subject string, remember bool) (redirectTo string, err error) {
+	body := map[string]any{
+		"subject":  subject,
+		"remember": remember,
+	}
+	var out acceptResponse
+	if err := c.do(http.MethodPut, "/oauth2/auth/requests/login/accept", "login_challenge", challenge, body, &out); err != nil {
+		return "", err
+	}
+	return out.RedirectTo, nil
+}
+
+// GetConsentRequest fetches the consent request identified by challenge.
+func (c *AdminClient) GetConsentRequest(challenge string) (*ConsentRequest, error) {
+	var out ConsentRequest
+	if err := c.do(http.MethodGet, "/oauth2/auth/requests/consent", "consent_challenge", challenge, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AcceptConsentRequest grants grantScope and grantAudience for the consent
+// request identified by challenge, and returns the URL the browser should
+// be redirected to next (back to the OAuth2 client).
+func (c *AdminClient) AcceptConsentRequest(challenge string, grantScope, grantAudience []string, remember bool) (redirectTo string, err error) {
+	body := map[string]any{
+		"grant_scope":                 grantScope,
+		"grant_access_token_audience": grantAudience,
+		"remember":                    remember,
+	}
+	var out acceptResponse
+	if err := c.do(http.MethodPut, "/oauth2/auth/requests/consent/accept", "consent_challenge", challenge, body, &out); err != nil {
+		return "", err
+	}
+	return out.RedirectTo, nil
+}
+
+func (c *AdminClient) do(method, path, challengeParam, challenge string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("oidc: marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	u := fmt.Sprintf("%s%s?%s=%s", c.baseURL, path, challengeParam, url.QueryEscape(challenge))
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return fmt.Errorf("oidc: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc: call %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("oidc: %s %s returned %s: %s", method, path, resp.Status, respBody)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("oidc: decode response from %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package oidc
+
+import "encoding/base64"
+
+// Subject derives the OIDC "sub" claim from a WebAuthn user handle. The
+// mapping is a pure, reversible encoding rather than a lookup, so any
+// passkey a user has registered can produce the same subject without a
+// round trip to storage.
+func Subject(userHandle []byte) string {
+	return base64.RawURLEncoding.EncodeToString(userHandle)
+}
+
+// UserHandle reverses Subject, recovering the WebAuthn user handle carried
+// in an OIDC subject claim.
+func UserHandle(subject string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(subject)
+}
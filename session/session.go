@@ -0,0 +1,203 @@
+// Package session manages the WebAuthn ceremony state exchanged between
+// the begin and finish steps of registration and login. Session data is
+// encrypted and carried in an HttpOnly cookie rather than kept in a
+// process-local map, so the server can be scaled horizontally and the
+// finish step no longer needs the caller to re-send a username.
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// ErrNotFound is returned when a request carries no valid session cookie.
+var ErrNotFound = errors.New("session: not found")
+
+// DefaultTTL bounds how long a registration or login ceremony may take to
+// complete before its session data is considered stale.
+const DefaultTTL = 5 * time.Minute
+
+// Backend stores the encrypted session blob referenced by a cookie.
+// CookieBackend, the default, stores the ciphertext directly in the
+// cookie value and needs nothing else. Operators running multiple
+// instances behind a load balancer can implement Backend over Redis (or
+// similar) instead, storing the blob server-side and keying it with a
+// short opaque token.
+type Backend interface {
+	// Put stores ciphertext for up to ttl and returns the token to embed
+	// in the cookie.
+	Put(ciphertext []byte, ttl time.Duration) (token string, err error)
+
+	// Get resolves token back to the ciphertext previously stored by Put.
+	// It returns ErrNotFound if token is unknown or has expired.
+	Get(token string) ([]byte, error)
+
+	// Delete discards any data stored under token.
+	Delete(token string) error
+}
+
+// Manager encrypts WebAuthn session data and carries it to the client via
+// cookies, backed by a pluggable Backend.
+type Manager struct {
+	backend Backend
+	gcm     cipher.AEAD
+	ttl     time.Duration
+}
+
+// NewManager creates a Manager that encrypts with key (must be 16, 24, or
+// 32 bytes, selecting AES-128/192/256) and stores tokens via backend.
+func NewManager(key []byte, backend Backend) (*Manager, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("session: create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("session: create gcm: %w", err)
+	}
+
+	return &Manager{backend: backend, gcm: gcm, ttl: DefaultTTL}, nil
+}
+
+// NewCookieManager creates a Manager whose Backend is the cookie itself,
+// requiring no server-side storage.
+func NewCookieManager(key []byte) (*Manager, error) {
+	return NewManager(key, CookieBackend{})
+}
+
+// Save encrypts data and sets it as cookieName on w.
+func (m *Manager) Save(w http.ResponseWriter, cookieName string, data *webauthn.SessionData) error {
+	return m.SaveValue(w, cookieName, m.ttl, data)
+}
+
+// Load reads cookieName from r, decrypts it, and returns the session data
+// it carries. It returns ErrNotFound if the cookie is absent or expired.
+func (m *Manager) Load(r *http.Request, cookieName string) (*webauthn.SessionData, error) {
+	var data webauthn.SessionData
+	if err := m.LoadValue(r, cookieName, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// SaveValue encrypts any JSON-marshalable value and sets it as cookieName on
+// w, valid for ttl. It underlies Save, and also lets callers outside the
+// ceremony begin/finish flow (e.g. a longer-lived signed-in identity) reuse
+// the same encrypted-cookie mechanism with their own value type and
+// lifetime.
+func (m *Manager) SaveValue(w http.ResponseWriter, cookieName string, ttl time.Duration, value any) error {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("session: marshal value: %w", err)
+	}
+
+	ciphertext, err := m.encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	token, err := m.backend.Put(ciphertext, ttl)
+	if err != nil {
+		return fmt.Errorf("session: store value: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+// LoadValue reads cookieName from r, decrypts it, and unmarshals it into
+// dest. It returns ErrNotFound if the cookie is absent or expired.
+func (m *Manager) LoadValue(r *http.Request, cookieName string, dest any) error {
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	ciphertext, err := m.backend.Get(cookie.Value)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	plaintext, err := m.decrypt(ciphertext)
+	if err != nil {
+		return fmt.Errorf("session: decrypt value: %w", err)
+	}
+
+	if err := json.Unmarshal(plaintext, dest); err != nil {
+		return fmt.Errorf("session: unmarshal value: %w", err)
+	}
+	return nil
+}
+
+// Clear deletes cookieName's backing data and expires it on the client.
+func (m *Manager) Clear(w http.ResponseWriter, r *http.Request, cookieName string) {
+	if cookie, err := r.Cookie(cookieName); err == nil {
+		m.backend.Delete(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (m *Manager) encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, m.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("session: generate nonce: %w", err)
+	}
+	return m.gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (m *Manager) decrypt(ciphertext []byte) ([]byte, error) {
+	nonceSize := m.gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("session: ciphertext too short")
+	}
+
+	nonce, sealed := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	return m.gcm.Open(nil, nonce, sealed, nil)
+}
+
+// CookieBackend is the default Backend: it needs no storage of its own
+// because the ciphertext is the token, base64-encoded for safe cookie
+// transport.
+type CookieBackend struct{}
+
+func (CookieBackend) Put(ciphertext []byte, _ time.Duration) (string, error) {
+	return base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+func (CookieBackend) Get(token string) ([]byte, error) {
+	ciphertext, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return ciphertext, nil
+}
+
+func (CookieBackend) Delete(string) error { return nil }
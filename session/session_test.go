@@ -0,0 +1,127 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testKey() []byte {
+	return []byte("01234567890123456789012345678901") // 32 bytes: AES-256
+}
+
+func TestSaveLoadValueRoundtrip(t *testing.T) {
+	manager, err := NewCookieManager(testKey())
+	if err != nil {
+		t.Fatalf("NewCookieManager() = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := manager.SaveValue(rec, "test-cookie", time.Minute, map[string]string{"hello": "world"}); err != nil {
+		t.Fatalf("SaveValue() = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	var got map[string]string
+	if err := manager.LoadValue(req, "test-cookie", &got); err != nil {
+		t.Fatalf("LoadValue() = %v", err)
+	}
+	if got["hello"] != "world" {
+		t.Fatalf("LoadValue() = %+v, want {hello: world}", got)
+	}
+}
+
+func TestLoadValueMissingCookie(t *testing.T) {
+	manager, err := NewCookieManager(testKey())
+	if err != nil {
+		t.Fatalf("NewCookieManager() = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	var got map[string]string
+	if err := manager.LoadValue(req, "test-cookie", &got); err != ErrNotFound {
+		t.Fatalf("LoadValue() with no cookie = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLoadValueRejectsTamperedCookie(t *testing.T) {
+	manager, err := NewCookieManager(testKey())
+	if err != nil {
+		t.Fatalf("NewCookieManager() = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := manager.SaveValue(rec, "test-cookie", time.Minute, map[string]string{"hello": "world"}); err != nil {
+		t.Fatalf("SaveValue() = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		c.Value = c.Value[:len(c.Value)-1] + "x"
+		req.AddCookie(c)
+	}
+
+	var got map[string]string
+	if err := manager.LoadValue(req, "test-cookie", &got); err == nil {
+		t.Fatalf("LoadValue() with tampered cookie = nil error, want decrypt failure")
+	}
+}
+
+func TestLoadValueRejectsWrongKey(t *testing.T) {
+	writer, err := NewCookieManager(testKey())
+	if err != nil {
+		t.Fatalf("NewCookieManager() = %v", err)
+	}
+	reader, err := NewCookieManager([]byte("10987654321098765432109876543210"))
+	if err != nil {
+		t.Fatalf("NewCookieManager() = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := writer.SaveValue(rec, "test-cookie", time.Minute, map[string]string{"hello": "world"}); err != nil {
+		t.Fatalf("SaveValue() = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	var got map[string]string
+	if err := reader.LoadValue(req, "test-cookie", &got); err == nil {
+		t.Fatalf("LoadValue() with wrong key = nil error, want decrypt failure")
+	}
+}
+
+func TestClearExpiresCookie(t *testing.T) {
+	manager, err := NewCookieManager(testKey())
+	if err != nil {
+		t.Fatalf("NewCookieManager() = %v", err)
+	}
+
+	saveRec := httptest.NewRecorder()
+	if err := manager.SaveValue(saveRec, "test-cookie", time.Minute, "value"); err != nil {
+		t.Fatalf("SaveValue() = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range saveRec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	clearRec := httptest.NewRecorder()
+	manager.Clear(clearRec, req, "test-cookie")
+
+	cookies := clearRec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Clear() set %d cookies, want 1", len(cookies))
+	}
+	if cookies[0].MaxAge >= 0 {
+		t.Fatalf("Clear() cookie MaxAge = %d, want negative", cookies[0].MaxAge)
+	}
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// MemoryStore is an in-memory reference implementation of CredentialStore.
+// State does not survive a process restart; it exists for local
+// development and tests.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	usersByName map[string]*UserRecord
+	credsByUser map[string][]CredentialRecord
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		usersByName: make(map[string]*UserRecord),
+		credsByUser: make(map[string][]CredentialRecord),
+	}
+}
+
+func (s *MemoryStore) GetUser(name string) (*UserRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.usersByName[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) GetUserByID(userID []byte) (*UserRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.usersByName {
+		if bytes.Equal(user.ID, userID) {
+			return user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) PutUser(user *UserRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usersByName[user.Name] = user
+	return nil
+}
+
+func (s *MemoryStore) AddCredential(userID []byte, cred webauthn.Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := string(userID)
+	s.credsByUser[key] = append(s.credsByUser[key], CredentialRecord{
+		Credential: cred,
+		CreatedAt:  time.Now(),
+	})
+	return nil
+}
+
+func (s *MemoryStore) UpdateCredentialCounter(credentialID []byte, counter uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, records := range s.credsByUser {
+		for i := range records {
+			if bytes.Equal(records[i].Credential.ID, credentialID) {
+				records[i].Credential.Authenticator.SignCount = counter
+				records[i].LastUsedAt = time.Now()
+				s.credsByUser[key] = records
+				return nil
+			}
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryStore) ListCredentialsByUser(userID []byte) ([]webauthn.Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := s.credsByUser[string(userID)]
+	creds := make([]webauthn.Credential, len(records))
+	for i, record := range records {
+		creds[i] = record.Credential
+	}
+	return creds, nil
+}
+
+func (s *MemoryStore) FindCredentialByID(credentialID []byte) (*webauthn.Credential, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key, records := range s.credsByUser {
+		for i := range records {
+			if bytes.Equal(records[i].Credential.ID, credentialID) {
+				cred := records[i].Credential
+				return &cred, []byte(key), nil
+			}
+		}
+	}
+	return nil, nil, ErrNotFound
+}
+
+func (s *MemoryStore) ListCredentialRecords(userID []byte) ([]CredentialRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.credsByUser[string(userID)], nil
+}
+
+func (s *MemoryStore) RenameCredential(credentialID []byte, nickname string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, records := range s.credsByUser {
+		for i := range records {
+			if bytes.Equal(records[i].Credential.ID, credentialID) {
+				records[i].Nickname = nickname
+				s.credsByUser[key] = records
+				return nil
+			}
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryStore) DeleteCredential(credentialID []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, records := range s.credsByUser {
+		for i := range records {
+			if bytes.Equal(records[i].Credential.ID, credentialID) {
+				s.credsByUser[key] = append(records[:i], records[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return ErrNotFound
+}
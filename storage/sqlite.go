@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore is a CredentialStore backed by a SQLite database, so enrolled
+// passkeys survive a server restart. In-flight ceremony session data is
+// handled separately by the session package.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if necessary) the SQLite database at path
+// and ensures its schema exists.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("storage: open sqlite database: %w", err)
+	}
+
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) migrate() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS users (
+			id           BLOB PRIMARY KEY,
+			name         TEXT NOT NULL UNIQUE,
+			display_name TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS credentials (
+			id           BLOB PRIMARY KEY,
+			user_id      BLOB NOT NULL REFERENCES users(id),
+			data         BLOB NOT NULL,
+			nickname     TEXT NOT NULL DEFAULT '',
+			created_at   DATETIME NOT NULL,
+			last_used_at DATETIME
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("storage: migrate sqlite schema: %w", err)
+	}
+	return nil
+}
+
+// Close releases the underlying database handle.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) GetUser(name string) (*UserRecord, error) {
+	row := s.db.QueryRow(`SELECT id, name, display_name FROM users WHERE name = ?`, name)
+
+	var user UserRecord
+	if err := row.Scan(&user.ID, &user.Name, &user.DisplayName); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("storage: get user: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *SQLiteStore) GetUserByID(userID []byte) (*UserRecord, error) {
+	row := s.db.QueryRow(`SELECT id, name, display_name FROM users WHERE id = ?`, userID)
+
+	var user UserRecord
+	if err := row.Scan(&user.ID, &user.Name, &user.DisplayName); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("storage: get user by id: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *SQLiteStore) PutUser(user *UserRecord) error {
+	_, err := s.db.Exec(
+		`INSERT INTO users (id, name, display_name) VALUES (?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET name = excluded.name, display_name = excluded.display_name`,
+		user.ID, user.Name, user.DisplayName,
+	)
+	if err != nil {
+		return fmt.Errorf("storage: put user: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AddCredential(userID []byte, cred webauthn.Credential) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return fmt.Errorf("storage: marshal credential: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO credentials (id, user_id, data, created_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET data = excluded.data`,
+		cred.ID, userID, data, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("storage: add credential: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UpdateCredentialCounter(credentialID []byte, counter uint32) error {
+	cred, _, err := s.FindCredentialByID(credentialID)
+	if err != nil {
+		return err
+	}
+	cred.Authenticator.SignCount = counter
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return fmt.Errorf("storage: marshal credential: %w", err)
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE credentials SET data = ?, last_used_at = ? WHERE id = ?`,
+		data, time.Now(), credentialID,
+	)
+	if err != nil {
+		return fmt.Errorf("storage: update credential counter: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListCredentialsByUser(userID []byte) ([]webauthn.Credential, error) {
+	rows, err := s.db.Query(`SELECT data FROM credentials WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("storage: list credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var creds []webauthn.Credential
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("storage: scan credential: %w", err)
+		}
+
+		var cred webauthn.Credential
+		if err := json.Unmarshal(data, &cred); err != nil {
+			return nil, fmt.Errorf("storage: unmarshal credential: %w", err)
+		}
+		creds = append(creds, cred)
+	}
+	return creds, rows.Err()
+}
+
+func (s *SQLiteStore) FindCredentialByID(credentialID []byte) (*webauthn.Credential, []byte, error) {
+	row := s.db.QueryRow(`SELECT user_id, data FROM credentials WHERE id = ?`, credentialID)
+
+	var userID, data []byte
+	if err := row.Scan(&userID, &data); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("storage: find credential: %w", err)
+	}
+
+	var cred webauthn.Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return nil, nil, fmt.Errorf("storage: unmarshal credential: %w", err)
+	}
+	return &cred, userID, nil
+}
+
+func (s *SQLiteStore) ListCredentialRecords(userID []byte) ([]CredentialRecord, error) {
+	rows, err := s.db.Query(
+		`SELECT data, nickname, created_at, last_used_at FROM credentials WHERE user_id = ?`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("storage: list credential records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []CredentialRecord
+	for rows.Next() {
+		var (
+			data       []byte
+			lastUsedAt sql.NullTime
+			record     CredentialRecord
+		)
+		if err := rows.Scan(&data, &record.Nickname, &record.CreatedAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("storage: scan credential record: %w", err)
+		}
+		if err := json.Unmarshal(data, &record.Credential); err != nil {
+			return nil, fmt.Errorf("storage: unmarshal credential: %w", err)
+		}
+		record.LastUsedAt = lastUsedAt.Time
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLiteStore) RenameCredential(credentialID []byte, nickname string) error {
+	res, err := s.db.Exec(`UPDATE credentials SET nickname = ? WHERE id = ?`, nickname, credentialID)
+	if err != nil {
+		return fmt.Errorf("storage: rename credential: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteCredential(credentialID []byte) error {
+	res, err := s.db.Exec(`DELETE FROM credentials WHERE id = ?`, credentialID)
+	if err != nil {
+		return fmt.Errorf("storage: delete credential: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
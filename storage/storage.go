@@ -0,0 +1,76 @@
+// Package storage persists WebAuthn users and their credentials so that
+// registered passkeys survive a server restart. In-flight ceremony
+// session data is handled separately by the session package.
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// ErrNotFound is returned when a requested user or credential does not exist.
+var ErrNotFound = errors.New("storage: not found")
+
+// UserRecord is the persisted representation of an application user.
+type UserRecord struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// CredentialRecord is a stored credential together with the bookkeeping
+// metadata the self-service credential management API exposes to users.
+type CredentialRecord struct {
+	Credential webauthn.Credential
+	Nickname   string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// CredentialStore persists users and the WebAuthn credentials enrolled
+// against them. Implementations must be safe for concurrent use.
+type CredentialStore interface {
+	// GetUser looks up a user by their unique name. It returns ErrNotFound
+	// if no such user exists.
+	GetUser(name string) (*UserRecord, error)
+
+	// GetUserByID looks up a user by their WebAuthn user handle, as
+	// returned in a discoverable-credential assertion. It returns
+	// ErrNotFound if no such user exists.
+	GetUserByID(userID []byte) (*UserRecord, error)
+
+	// PutUser creates or updates a user record.
+	PutUser(user *UserRecord) error
+
+	// AddCredential enrolls cred against the user identified by userID.
+	AddCredential(userID []byte, cred webauthn.Credential) error
+
+	// UpdateCredentialCounter persists a new signature counter for the
+	// credential identified by credentialID, e.g. after a successful
+	// assertion, so cloned authenticators can be detected.
+	UpdateCredentialCounter(credentialID []byte, counter uint32) error
+
+	// ListCredentialsByUser returns every credential enrolled for userID.
+	ListCredentialsByUser(userID []byte) ([]webauthn.Credential, error)
+
+	// FindCredentialByID looks up a credential and the ID of the user that
+	// owns it, regardless of username. It returns ErrNotFound if no
+	// credential with that ID has been enrolled.
+	FindCredentialByID(credentialID []byte) (cred *webauthn.Credential, userID []byte, err error)
+
+	// ListCredentialRecords returns every credential enrolled for userID
+	// together with its management metadata, for the self-service
+	// credential listing API.
+	ListCredentialRecords(userID []byte) ([]CredentialRecord, error)
+
+	// RenameCredential sets the user-supplied nickname of the credential
+	// identified by credentialID. It returns ErrNotFound if no such
+	// credential has been enrolled.
+	RenameCredential(credentialID []byte, nickname string) error
+
+	// DeleteCredential revokes the credential identified by credentialID.
+	// It returns ErrNotFound if no such credential has been enrolled.
+	DeleteCredential(credentialID []byte) error
+}
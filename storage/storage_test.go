@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// TestCredentialStoreRoundtrip exercises the full CredentialStore contract
+// against every implementation, so a backend-specific bug (e.g. a SQL
+// query touching the wrong column) fails here instead of in production.
+func TestCredentialStoreRoundtrip(t *testing.T) {
+	backends := map[string]func(t *testing.T) CredentialStore{
+		"memory": func(t *testing.T) CredentialStore {
+			return NewMemoryStore()
+		},
+		"sqlite": func(t *testing.T) CredentialStore {
+			store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("NewSQLiteStore() = %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+
+	for name, newStore := range backends {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+
+			userID := []byte("user-1")
+			if err := store.PutUser(&UserRecord{ID: userID, Name: "alice", DisplayName: "Alice"}); err != nil {
+				t.Fatalf("PutUser() = %v", err)
+			}
+
+			byName, err := store.GetUser("alice")
+			if err != nil {
+				t.Fatalf("GetUser() = %v", err)
+			}
+			if !bytes.Equal(byName.ID, userID) {
+				t.Fatalf("GetUser().ID = %x, want %x", byName.ID, userID)
+			}
+
+			byID, err := store.GetUserByID(userID)
+			if err != nil {
+				t.Fatalf("GetUserByID() = %v", err)
+			}
+			if byID.Name != "alice" {
+				t.Fatalf("GetUserByID().Name = %q, want %q", byID.Name, "alice")
+			}
+
+			if _, err := store.GetUser("nobody"); err != ErrNotFound {
+				t.Fatalf("GetUser() for unknown user = %v, want ErrNotFound", err)
+			}
+
+			credID := []byte("cred-1")
+			if err := store.AddCredential(userID, webauthn.Credential{ID: credID}); err != nil {
+				t.Fatalf("AddCredential() = %v", err)
+			}
+
+			creds, err := store.ListCredentialsByUser(userID)
+			if err != nil {
+				t.Fatalf("ListCredentialsByUser() = %v", err)
+			}
+			if len(creds) != 1 || !bytes.Equal(creds[0].ID, credID) {
+				t.Fatalf("ListCredentialsByUser() = %+v, want one credential %x", creds, credID)
+			}
+
+			foundCred, foundUserID, err := store.FindCredentialByID(credID)
+			if err != nil {
+				t.Fatalf("FindCredentialByID() = %v", err)
+			}
+			if !bytes.Equal(foundCred.ID, credID) || !bytes.Equal(foundUserID, userID) {
+				t.Fatalf("FindCredentialByID() = (%x, %x), want (%x, %x)", foundCred.ID, foundUserID, credID, userID)
+			}
+
+			if err := store.UpdateCredentialCounter(credID, 7); err != nil {
+				t.Fatalf("UpdateCredentialCounter() = %v", err)
+			}
+
+			records, err := store.ListCredentialRecords(userID)
+			if err != nil {
+				t.Fatalf("ListCredentialRecords() = %v", err)
+			}
+			if len(records) != 1 || records[0].Credential.Authenticator.SignCount != 7 {
+				t.Fatalf("ListCredentialRecords() = %+v, want SignCount 7", records)
+			}
+			if records[0].LastUsedAt.IsZero() {
+				t.Fatalf("ListCredentialRecords()[0].LastUsedAt is zero, want set by UpdateCredentialCounter")
+			}
+
+			if err := store.RenameCredential(credID, "my phone"); err != nil {
+				t.Fatalf("RenameCredential() = %v", err)
+			}
+			if records, err = store.ListCredentialRecords(userID); err != nil {
+				t.Fatalf("ListCredentialRecords() = %v", err)
+			} else if len(records) != 1 || records[0].Nickname != "my phone" {
+				t.Fatalf("ListCredentialRecords() = %+v, want nickname %q", records, "my phone")
+			}
+
+			if err := store.DeleteCredential(credID); err != nil {
+				t.Fatalf("DeleteCredential() = %v", err)
+			}
+			if _, _, err := store.FindCredentialByID(credID); err != ErrNotFound {
+				t.Fatalf("FindCredentialByID() after delete = %v, want ErrNotFound", err)
+			}
+			if err := store.RenameCredential(credID, "x"); err != ErrNotFound {
+				t.Fatalf("RenameCredential() on deleted credential = %v, want ErrNotFound", err)
+			}
+			if err := store.DeleteCredential(credID); err != ErrNotFound {
+				t.Fatalf("DeleteCredential() on already-deleted credential = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}